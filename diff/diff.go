@@ -0,0 +1,110 @@
+// Package diff compares two file-inventory JSON snapshots, reporting
+// which files were added, removed, or modified, plus block-level
+// dedup statistics derived from any chunk hashes the scans recorded.
+package diff
+
+import (
+	"sort"
+
+	"github.com/jeepinbird/file-inventory/scanner"
+)
+
+// ChunkStats summarizes how much of the new scan's content-defined
+// chunk data already existed in the old scan, giving a
+// syncthing/rsync-style view of how much could be synced as deltas
+// instead of whole files.
+type ChunkStats struct {
+	TotalChunks  int   `json:"total_chunks"`
+	ReusedChunks int   `json:"reused_chunks"`
+	ReusedBytes  int64 `json:"reused_bytes"`
+}
+
+// Report is the result of comparing two inventories.
+type Report struct {
+	Added    []string   `json:"added"`
+	Removed  []string   `json:"removed"`
+	Modified []string   `json:"modified"`
+	Chunks   ChunkStats `json:"chunk_stats"`
+}
+
+// Compare reports the differences between an older and a newer
+// inventory scan.
+func Compare(oldFiles, newFiles []scanner.FileInfo) Report {
+	oldIndex := indexByPath(oldFiles)
+	newIndex := indexByPath(newFiles)
+
+	var added, removed, modified []string
+	for path, nf := range newIndex {
+		of, ok := oldIndex[path]
+		if !ok {
+			added = append(added, path)
+			continue
+		}
+		if !sameContent(of, nf) {
+			modified = append(modified, path)
+		}
+	}
+	for path := range oldIndex {
+		if _, ok := newIndex[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+
+	return Report{
+		Added:    added,
+		Removed:  removed,
+		Modified: modified,
+		Chunks:   chunkStats(oldFiles, newFiles),
+	}
+}
+
+func indexByPath(files []scanner.FileInfo) map[string]scanner.FileInfo {
+	idx := make(map[string]scanner.FileInfo, len(files))
+	for _, f := range files {
+		idx[f.Path+f.Name] = f
+	}
+	return idx
+}
+
+// sameContent reports whether two entries for the same path represent
+// the same file content. It prefers comparing a hash algorithm the two
+// scans have in common; if they share none (e.g. one scan used --hash
+// blake3 and the other sha256), it falls back to size and mtime.
+func sameContent(a, b scanner.FileInfo) bool {
+	if a.Size != b.Size {
+		return false
+	}
+	for algo, sum := range a.Hashes {
+		if otherSum, ok := b.Hashes[algo]; ok {
+			return sum == otherSum
+		}
+	}
+	return a.ModifiedDate == b.ModifiedDate
+}
+
+// chunkStats counts how many of newFiles' chunks have a hash that also
+// appears somewhere in oldFiles, as a proxy for how many bytes could be
+// synced incrementally rather than re-transferred in full.
+func chunkStats(oldFiles, newFiles []scanner.FileInfo) ChunkStats {
+	oldChunks := make(map[string]struct{})
+	for _, f := range oldFiles {
+		for _, c := range f.Chunks {
+			oldChunks[c.Hash] = struct{}{}
+		}
+	}
+
+	var stats ChunkStats
+	for _, f := range newFiles {
+		for _, c := range f.Chunks {
+			stats.TotalChunks++
+			if _, ok := oldChunks[c.Hash]; ok {
+				stats.ReusedChunks++
+				stats.ReusedBytes += int64(c.Length)
+			}
+		}
+	}
+	return stats
+}
@@ -1,42 +1,34 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"runtime"
-	"sync"
+	"syscall"
 
-	"github.com/schollz/progressbar/v3"
+	"github.com/jeepinbird/file-inventory/diff"
+	"github.com/jeepinbird/file-inventory/scanner"
+	"golang.org/x/term"
 )
 
-// FileInfo struct represents information about a file
-type FileInfo struct {
-	Name         string `json:"name"`
-	Path         string `json:"path"`
-	ModifiedDate string `json:"modified_date"`
-	SHA256Hash   string `json:"sha256_hash"`
-}
-
-// countFiles function counts the total number of files in a directory and its children
-func countFiles(root string) (int, error) {
+// countFiles counts the total number of files in a directory and its
+// children, for sizing the progress bar up front.
+func countFiles(fs scanner.Filesystem, root string) (int, error) {
 	var fileCount int
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	err := fs.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			// Handle permission errors gracefully during counting too
 			if os.IsPermission(err) {
-				fmt.Printf("Permission error (count): %s (skipping)\n", path) // Info
+				fmt.Fprintf(os.Stderr, "Permission error (count): %s (skipping)\n", path)
 				if info != nil && info.IsDir() {
 					return filepath.SkipDir
 				}
-				return nil // Skip file
+				return nil
 			}
-			return err // Propagate other errors
+			return err
 		}
 		if !info.IsDir() {
 			fileCount++
@@ -46,231 +38,172 @@ func countFiles(root string) (int, error) {
 	return fileCount, err
 }
 
-// calculateSHA256 function calculates the SHA256 hash of a file
-func calculateSHA256(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", err
+// newReporter picks a Reporter based on the --quiet/--progress flags: an
+// explicit choice always wins, otherwise it falls back to a TTY progress
+// bar when stderr is a terminal and a no-op reporter when it isn't (e.g.
+// piped into a file or run from cron), so the default stays quiet for
+// non-interactive invocations without requiring --quiet everywhere.
+func newReporter(quiet bool, progress string, totalFiles int) scanner.Reporter {
+	if quiet {
+		return scanner.NoopReporter{}
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			// Maybe log this, but don't make it a fatal error for the hash
-			fmt.Printf("Warning: error closing file %s: %v\n", filePath, err)
+	switch progress {
+	case "json":
+		return scanner.NewJSONReporter(os.Stderr)
+	case "none":
+		return scanner.NoopReporter{}
+	case "bar":
+		return scanner.NewProgressBarReporter(totalFiles)
+	case "auto", "":
+		if term.IsTerminal(int(os.Stderr.Fd())) {
+			return scanner.NewProgressBarReporter(totalFiles)
 		}
-	}()
-
-	hash := sha256.New()
-	// Use a buffer potentially? io.Copy usually does this well internally.
-	// buf := make([]byte, 32*1024) // Example buffer
-	// if _, err := io.CopyBuffer(hash, file, buf); err != nil {
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
-	}
-
-	hashInBytes := hash.Sum(nil)
-	return hex.EncodeToString(hashInBytes), nil
-}
-
-// saveToJSON function saves the file information to a JSON file
-func saveToJSON(files []FileInfo, outputPath string) error {
-	jsonData, err := json.Marshal(files) // Simply Marshal the output
-	if err != nil {
-		return err
+		return scanner.NoopReporter{}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown --progress value %q, falling back to auto\n", progress)
+		if term.IsTerminal(int(os.Stderr.Fd())) {
+			return scanner.NewProgressBarReporter(totalFiles)
+		}
+		return scanner.NoopReporter{}
 	}
-	return os.WriteFile(outputPath, jsonData, 0644)
 }
 
 func main() {
-	// Suggest default workers based on CPU count
-	defaultWorkers := runtime.NumCPU()
-	if defaultWorkers < 4 {
-		defaultWorkers = 4 // Set a minimum if few cores
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
 	}
+	runScan()
+}
 
+func runScan() {
 	// Define command line flags
 	rootDir := flag.String("dir", ".", "Directory to scan")
-	outputFile := flag.String("output", "file_inventory.json", "Output JSON file")
-	// Use the calculated default as the default flag value
-	workerCount := flag.Int("workers", defaultWorkers, "Number of concurrent hashing workers")
+	outputFile := flag.String("output", "file_inventory.json", "Output file, or \"-\" for stdout")
+	ioWorkers := flag.Int("io-workers", 0, "Number of concurrent file reads (0 = autodetect from the root device's rotational flag)")
+	hashWorkers := flag.Int("hash-workers", 0, "Number of concurrent hash computations (0 = NumCPU)")
+	maxBytesInFlight := flag.Int64("max-bytes-in-flight", scanner.DefaultMaxBytesInFlight, "Maximum bytes of file content read but not yet hashed")
+	resumeFrom := flag.String("resume", "", "Path to a previous inventory JSON to resume from, skipping unchanged files")
+	format := flag.String("format", string(scanner.FormatJSON), "Output format: json (single array) or ndjson (one object per line, streamed)")
+	hashAlgos := flag.String("hash", "sha256", "Comma-separated hash algorithms to compute: sha256, blake3, xxh64")
+	chunkHashes := flag.Bool("chunk-hashes", false, "Additionally compute content-defined chunk hashes for block-level dedup analytics")
+	dbPath := flag.String("db", "", "Path to a bbolt database that caches hashes across runs, skipping unchanged files and reporting deletions")
+	quiet := flag.Bool("quiet", false, "Suppress progress output entirely")
+	progress := flag.String("progress", "auto", "Progress reporting: auto, bar, json (one event per line on stderr), or none")
 	flag.Parse()
 
-	// --- Count files first for progress bar and slice allocation ---
-	fmt.Printf("Counting files in directory: %s...\n", *rootDir)
-	totalFiles, err := countFiles(*rootDir)
+	// Cancel the root context on SIGINT/SIGTERM so an interrupted scan
+	// stops cleanly and still flushes whatever it finished so far.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fs := scanner.OSFilesystem{}
+
+	fmt.Fprintf(os.Stderr, "Counting files in directory: %s...\n", *rootDir)
+	totalFiles, err := countFiles(fs, *rootDir)
 	if err != nil {
-		fmt.Printf("Error counting files: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error counting files: %v\n", err)
 		os.Exit(1)
 	}
-	if totalFiles == 0 {
-		fmt.Println("No files found to process.")
-		// Create an empty JSON array?
-		err := saveToJSON([]FileInfo{}, *outputFile) // Save empty results
-		if err != nil {
-			fmt.Printf("Error saving empty JSON: %v\n", err)
+	fmt.Fprintf(os.Stderr, "Found %d files. Starting scan...\n", totalFiles)
+
+	var out *os.File
+	if *outputFile == "-" {
+		out = os.Stdout
+	} else {
+		var createErr error
+		out, createErr = os.Create(*outputFile)
+		if createErr != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", createErr)
 			os.Exit(1)
 		}
-		fmt.Printf("Results saved to %s\n", *outputFile)
-		os.Exit(0)
+		defer out.Close()
 	}
-	fmt.Printf("Found %d files. Starting scan with %d workers...\n", totalFiles, *workerCount)
-
-	bar := progressbar.Default(int64(totalFiles))
-
-	// --- Setup Channels and Semaphore ---
-	// Use a semaphore to limit concurrent goroutines for hashing
-	sem := make(chan struct{}, *workerCount)
-	// Buffered channels are good practice here
-	resultsChan := make(chan FileInfo, *workerCount)
-	errChan := make(chan error, *workerCount) // Collect errors from goroutines
-	var wg sync.WaitGroup                     // To wait for all hashing goroutines
 
-	// --- Goroutine to collect results ---
-	var files = make([]FileInfo, 0, totalFiles) // Pre-allocate slice
-	doneCollecting := make(chan struct{})
-	go func() {
-		for res := range resultsChan {
-			files = append(files, res)
-		}
-		close(doneCollecting) // Signal that collection is finished
-	}()
-
-	var errorCount int
-	var errorMutex sync.Mutex
-	errorWg := sync.WaitGroup{} // Use WaitGroup
-	errorWg.Add(1)
-	go func() {
-		defer errorWg.Done()
-		for procErr := range errChan {
-			fmt.Printf("Processing error: %v\n", procErr)
-			errorMutex.Lock()
-			errorCount++
-			errorMutex.Unlock()
-		}
-	}()
+	result, err := scanner.Scan(ctx, fs, scanner.Options{
+		RootDir:          *rootDir,
+		IOWorkers:        *ioWorkers,
+		HashWorkers:      *hashWorkers,
+		MaxBytesInFlight: *maxBytesInFlight,
+		ResumeFrom:       *resumeFrom,
+		Hash:             *hashAlgos,
+		ChunkHashes:      *chunkHashes,
+		DBPath:           *dbPath,
+		Format:           scanner.Format(*format),
+		Output:           out,
+		Reporter:         newReporter(*quiet, *progress, totalFiles),
+	})
 
-	// --- Walk the directory tree (still serial walk, but concurrent processing) ---
-	walkErr := filepath.Walk(*rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			// Gracefully handle permission errors
-			if os.IsPermission(err) {
-				fmt.Printf("Permission error accessing: %s (skipping)\n", path)
-				// If it's a directory we can't enter, skip its contents
-				if info != nil && info.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil // Skip the file if permission error on the file itself
-			}
-			// Report other walk errors but continue if possible
-			fmt.Printf("Error accessing %s: %v (skipping)\n", path, err)
-			errChan <- fmt.Errorf("walk error accessing %s: %w", path, err) // Send to *concurrent* reader
-			return nil                                                      // Returning nil tries to continue the walk
-		}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
 
-		// Skip directories
-		if info.IsDir() {
-			return nil
-		}
+	if result.Cancelled {
+		fmt.Fprintln(os.Stderr, "Scan cancelled; flushing partial results...")
+	}
 
-		// Skip "special" files
-		fileMode := info.Mode()
-		if fileMode&os.ModeNamedPipe != 0 ||
-			fileMode&os.ModeSocket != 0 ||
-			fileMode&os.ModeDevice != 0 ||
-			fileMode&os.ModeCharDevice != 0 ||
-			fileMode&os.ModeSymlink != 0 { // Also good to skip symlinks or resolve them carefully
+	if result.ErrorCount > 0 {
+		fmt.Fprintf(os.Stderr, "Encountered %d errors during processing.\n", result.ErrorCount)
+	}
 
-			fmt.Printf("Skipping special file or symlink: %s\n", path)
-			// Increment bar since it was counted but won't be processed by a worker.
-			// Ensure bar is thread-safe or handle this carefully.
-			// Since bar.Add is called *inside* the worker's defer now,
-			// we MUST call it here too, otherwise the count will be off.
-			if err := bar.Add(1); err != nil {
-				fmt.Printf("error updating progress bar for skipped file: %v\n", err)
-			}
-			return nil // Skip, don't wg.Add or launch goroutine
+	if len(result.Deleted) > 0 {
+		fmt.Fprintf(os.Stderr, "%d files present in the db but not found this scan (deleted):\n", len(result.Deleted))
+		for _, path := range result.Deleted {
+			fmt.Fprintf(os.Stderr, "  %s\n", path)
 		}
+	}
 
-		// --- Process the file concurrently ---
-		wg.Add(1) // Increment counter before starting goroutine
-
-		// Acquire semaphore - this blocks if workerCount goroutines are already running
-		sem <- struct{}{}
-
-		// Launch goroutine to process this file
-		go func(filePath string, fileInfo os.FileInfo) {
-			// Release semaphore and decrement counter when done
-			defer func() {
-				<-sem
-				wg.Done()
-			}()
-
-			// Calculate SHA256 hash
-			hash, hashErr := calculateSHA256(filePath)
-			if hashErr != nil {
-				// Report error calculating hash
-				errChan <- fmt.Errorf("error hashing %s: %w", filePath, hashErr)
-				// Update progress bar even if there's an error
-				if err := bar.Add(1); err != nil {
-					fmt.Printf("error updating progress bar: %v\n", err)
-				}
-				return // Don't send result if hashing failed
-			}
-
-			// Extract info (use fileInfo passed in, NO redundant os.Stat)
-			dir, fileName := filepath.Split(filePath)
-			formattedModTime := fileInfo.ModTime().UTC().Format("2006-01-02T15:04:05Z")
-
-			// Create FileInfo object and send to results channel
-			result := FileInfo{
-				Name:         fileName,
-				Path:         dir,
-				ModifiedDate: formattedModTime,
-				SHA256Hash:   hash,
-			}
-			resultsChan <- result
-
-			// Update progress bar after processing is complete
-			if err := bar.Add(1); err != nil {
-				fmt.Printf("error updating progress bar: %v\n", err)
-			}
-
-		}(path, info) // Pass current path and info to the goroutine!
-
-		return nil // Continue walk
-	})
-
-	// --- Wait for completion and cleanup ---
-
-	// Wait for all file processing goroutines to finish
-	wg.Wait()
-
-	// Close channels: No more results or errors will be sent
-	close(resultsChan)
-	close(errChan)
+	fmt.Fprintf(os.Stderr, "\nProcessed %d files.\n", result.FileCount)
+	if *outputFile != "-" {
+		fmt.Fprintf(os.Stderr, "Results saved to %s\n", *outputFile)
+	}
 
-	// Wait for the results collection goroutine to finish
-	<-doneCollecting
+	if result.Cancelled {
+		os.Exit(130)
+	}
+}
 
-	errorWg.Wait() // Wait for the error collection goroutine
+// runDiff implements the "file-inventory diff <old.json> <new.json>"
+// subcommand: it loads two previously saved inventories and reports
+// added/removed/modified files plus chunk reuse statistics.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Parse(args)
 
-	// Check for critical error during the walk itself
-	if walkErr != nil {
-		fmt.Printf("Critical error during directory walk: %v\n", walkErr)
-		// Depending on the error, you might still want to save partial results
+	if fs.NArg() != 2 {
+		fmt.Println("usage: file-inventory diff <old.json> <new.json>")
+		os.Exit(1)
 	}
 
-	// --- Use errorCount directly ---
-	if errorCount > 0 {
-		fmt.Printf("Encountered %d errors during processing.\n", errorCount)
+	oldFiles, err := loadInventory(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", fs.Arg(0), err)
+		os.Exit(1)
+	}
+	newFiles, err := loadInventory(fs.Arg(1))
+	if err != nil {
+		fmt.Printf("Error loading %s: %v\n", fs.Arg(1), err)
+		os.Exit(1)
 	}
 
-	// --- Save results ---
-	fmt.Printf("\nProcessed %d files.\n", len(files))
-	err = saveToJSON(files, *outputFile)
+	report := diff.Compare(oldFiles, newFiles)
+	data, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
-		fmt.Printf("Error saving to JSON: %v\n", err)
+		fmt.Printf("Error formatting report: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Println(string(data))
+}
 
-	fmt.Printf("Results saved to %s\n", *outputFile)
+func loadInventory(path string) ([]scanner.FileInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []scanner.FileInfo
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
 }
@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Content-defined chunking parameters: a 48-byte rolling window (the
+// same window size LBFS popularized), with chunk boundaries targeting
+// min=2KiB/avg=8KiB/max=64KiB. These let two FileInfo entries be diffed
+// for shared chunks even when most of a file has been rewritten.
+const (
+	cdcWindowSize = 48
+	cdcMinSize    = 2 << 10  // 2 KiB
+	cdcMaxSize    = 64 << 10 // 64 KiB
+
+	// cdcMaskBits is chosen so a boundary occurs on average every 2^bits
+	// bytes: for random data, P(low bits all zero) = 1/2^bits, so
+	// bits = log2(8 KiB) gives an ~8 KiB average chunk size.
+	cdcMaskBits = 13
+	cdcMask     = uint64(1)<<cdcMaskBits - 1
+
+	// cdcRollingPrime is the multiplier for a Rabin-Karp-style rolling
+	// hash; any large odd constant works, so we reuse the FNV-1a 64-bit
+	// prime rather than introduce a new one.
+	cdcRollingPrime = 1099511628211
+)
+
+// cdcWindowPow is cdcRollingPrime^cdcWindowSize, precomputed so the byte
+// leaving the trailing edge of the window can be subtracted out in O(1)
+// per byte instead of rehashing the whole window.
+var cdcWindowPow = func() uint64 {
+	p := uint64(1)
+	for i := 0; i < cdcWindowSize; i++ {
+		p *= cdcRollingPrime
+	}
+	return p
+}()
+
+// ChunkInfo describes one content-defined chunk of a file's data.
+type ChunkInfo struct {
+	Offset int64  `json:"offset"`
+	Length int    `json:"length"`
+	Hash   string `json:"hash"`
+}
+
+// chunkData splits data into content-defined chunks: a rolling hash is
+// maintained over the trailing cdcWindowSize bytes, and a boundary is
+// cut once the chunk is at least cdcMinSize long and the rolling hash's
+// low cdcMaskBits bits are all zero, or once it reaches cdcMaxSize
+// regardless. Each chunk is hashed independently with SHA256.
+func chunkData(data []byte) []ChunkInfo {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var chunks []ChunkInfo
+	var h uint64
+	start := 0
+
+	for i, b := range data {
+		h = h*cdcRollingPrime + uint64(b)
+		if i-start >= cdcWindowSize {
+			h -= uint64(data[i-cdcWindowSize]) * cdcWindowPow
+		}
+
+		length := i - start + 1
+		if (length >= cdcMinSize && h&cdcMask == 0) || length >= cdcMaxSize {
+			chunks = append(chunks, newChunk(data, start, i+1))
+			start = i + 1
+			h = 0
+		}
+	}
+	if start < len(data) {
+		chunks = append(chunks, newChunk(data, start, len(data)))
+	}
+	return chunks
+}
+
+func newChunk(data []byte, start, end int) ChunkInfo {
+	sum := sha256.Sum256(data[start:end])
+	return ChunkInfo{
+		Offset: int64(start),
+		Length: end - start,
+		Hash:   hex.EncodeToString(sum[:]),
+	}
+}
@@ -0,0 +1,81 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+func TestChunkDataEmpty(t *testing.T) {
+	if chunks := chunkData(nil); chunks != nil {
+		t.Fatalf("chunkData(nil) = %v, want nil", chunks)
+	}
+}
+
+func TestChunkDataSmallerThanMinSizeIsOneChunk(t *testing.T) {
+	data := bytes.Repeat([]byte{0x42}, cdcMinSize-1)
+	chunks := chunkData(data)
+	if len(chunks) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(chunks))
+	}
+	if chunks[0].Offset != 0 || chunks[0].Length != len(data) {
+		t.Fatalf("chunk = %+v, want offset 0 length %d", chunks[0], len(data))
+	}
+}
+
+// All-zero input keeps the rolling hash at zero throughout, so a boundary
+// is cut as soon as a chunk reaches cdcMinSize: every chunk but the last
+// should be exactly cdcMinSize long.
+func TestChunkDataCutsAtMinSizeOnConstantData(t *testing.T) {
+	data := bytes.Repeat([]byte{0}, cdcMinSize*3+10)
+	chunks := chunkData(data)
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 4", len(chunks))
+	}
+	for i, c := range chunks[:3] {
+		if c.Length != cdcMinSize {
+			t.Fatalf("chunk %d length = %d, want %d", i, c.Length, cdcMinSize)
+		}
+	}
+	if chunks[3].Length != 10 {
+		t.Fatalf("final chunk length = %d, want 10", chunks[3].Length)
+	}
+}
+
+// No chunk may ever exceed cdcMaxSize, and chunks must tile the input
+// exactly: contiguous, non-overlapping, and reproducing the original
+// bytes when hashed independently.
+func TestChunkDataBoundsAndCoverage(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	data := make([]byte, cdcMaxSize*3+1234)
+	r.Read(data)
+
+	chunks := chunkData(data)
+	if len(chunks) == 0 {
+		t.Fatal("expected at least one chunk")
+	}
+
+	offset := 0
+	for i, c := range chunks {
+		if c.Offset != int64(offset) {
+			t.Fatalf("chunk %d offset = %d, want %d (gap or overlap)", i, c.Offset, offset)
+		}
+		if c.Length <= 0 || c.Length > cdcMaxSize {
+			t.Fatalf("chunk %d length = %d, want in (0, %d]", i, c.Length, cdcMaxSize)
+		}
+		if i < len(chunks)-1 && c.Length < cdcMinSize {
+			t.Fatalf("non-final chunk %d length = %d, want >= %d", i, c.Length, cdcMinSize)
+		}
+
+		sum := sha256.Sum256(data[offset : offset+c.Length])
+		if got, want := c.Hash, hex.EncodeToString(sum[:]); got != want {
+			t.Fatalf("chunk %d hash = %s, want %s", i, got, want)
+		}
+		offset += c.Length
+	}
+	if offset != len(data) {
+		t.Fatalf("chunks covered %d bytes, want %d", offset, len(data))
+	}
+}
@@ -0,0 +1,42 @@
+package scanner
+
+import "strconv"
+
+// FileInfo struct represents information about a scanned file
+type FileInfo struct {
+	Name         string            `json:"name"`
+	Path         string            `json:"path"`
+	Size         int64             `json:"size"`
+	ModifiedDate string            `json:"modified_date"`
+	Hashes       map[string]string `json:"hashes"`
+	Chunks       []ChunkInfo       `json:"chunks,omitempty"`
+}
+
+// resumeKey returns the identity used to decide whether a file has changed
+// since a previous scan: path, modification time and size. The hash is
+// deliberately excluded since computing it is the expensive step we're
+// trying to avoid on unchanged files.
+func (f FileInfo) resumeKey() string {
+	return f.Path + f.Name + "|" + f.ModifiedDate + "|" + strconv.FormatInt(f.Size, 10)
+}
+
+// satisfies reports whether f already carries everything the current
+// invocation asked for: a digest for every requested hasher, plus chunk
+// data if wantChunks is set. A cached entry from a run with a narrower
+// --hash or no --chunk-hashes must not be reused as-is, or the output
+// would silently be missing the algorithms/chunks this run asked for.
+func (f FileInfo) satisfies(hashers []Hasher, wantChunks bool) bool {
+	return hasRequestedData(f.Hashes, f.Chunks, hashers, wantChunks)
+}
+
+// hasRequestedData is the shared check behind FileInfo.satisfies and
+// dbEntry.satisfies: every requested hasher must already have a digest,
+// and chunk data must be present if wantChunks is set.
+func hasRequestedData(hashes map[string]string, chunks []ChunkInfo, hashers []Hasher, wantChunks bool) bool {
+	for _, h := range hashers {
+		if _, ok := hashes[h.Name()]; !ok {
+			return false
+		}
+	}
+	return !wantChunks || chunks != nil
+}
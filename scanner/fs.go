@@ -0,0 +1,34 @@
+package scanner
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Filesystem abstracts the file operations the scanner needs so that the
+// walk-and-hash pipeline can run against backends other than the local
+// disk (e.g. SFTP, or an archive treated as its own tree) without
+// changing any of the scanning logic itself.
+type Filesystem interface {
+	// Walk mirrors filepath.Walk: it calls fn for every file and directory
+	// rooted at root, in the same order and with the same SkipDir
+	// semantics.
+	Walk(root string, fn filepath.WalkFunc) error
+
+	// Open opens path for reading.
+	Open(path string) (io.ReadCloser, error)
+}
+
+// OSFilesystem implements Filesystem against the local operating system's
+// filesystem. It is the default used by main when no other backend is
+// configured.
+type OSFilesystem struct{}
+
+func (OSFilesystem) Walk(root string, fn filepath.WalkFunc) error {
+	return filepath.Walk(root, fn)
+}
+
+func (OSFilesystem) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash"
+	"io"
+	"runtime"
+)
+
+// defaultHashWorkers returns a sane default for --hash-workers when the
+// user hasn't set one: hashing is CPU-bound, so one worker per core.
+func defaultHashWorkers() int {
+	return runtime.NumCPU()
+}
+
+// readFile reads the full contents of path (opened through fs) into a
+// pooled buffer, returning the buffer and the number of bytes read. The
+// caller is responsible for returning the buffer to the pool via
+// putBuffer once it's done with it.
+func readFile(fs Filesystem, path string) (*bytes.Buffer, int64, error) {
+	file, err := fs.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	buf := getBuffer()
+	n, err := buf.ReadFrom(file)
+	if err != nil {
+		putBuffer(buf)
+		return nil, 0, err
+	}
+	return buf, n, nil
+}
+
+// hashBuffer runs every hasher over buf's contents in a single pass via
+// io.MultiWriter, returning a map of algorithm name to hex digest.
+func hashBuffer(buf *bytes.Buffer, hashers []Hasher) map[string]string {
+	sums := make([]hash.Hash, len(hashers))
+	writers := make([]io.Writer, len(hashers))
+	for i, h := range hashers {
+		sums[i] = h.New()
+		writers[i] = sums[i]
+	}
+
+	// buf.Bytes() is safe to read without consuming buf, unlike
+	// io.Copy(w, buf) which would drain it. hash.Hash.Write never
+	// returns an error, so MultiWriter can't either here.
+	_, _ = io.MultiWriter(writers...).Write(buf.Bytes())
+
+	result := make(map[string]string, len(hashers))
+	for i, h := range hashers {
+		result[h.Name()] = hex.EncodeToString(sums[i].Sum(nil))
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/zeebo/blake3"
+)
+
+// Hasher names and constructs one of the digest algorithms --hash can
+// select.
+type Hasher interface {
+	Name() string
+	New() hash.Hash
+}
+
+type sha256Hasher struct{}
+
+func (sha256Hasher) Name() string   { return "sha256" }
+func (sha256Hasher) New() hash.Hash { return sha256.New() }
+
+type blake3Hasher struct{}
+
+func (blake3Hasher) Name() string   { return "blake3" }
+func (blake3Hasher) New() hash.Hash { return blake3.New() }
+
+type xxh64Hasher struct{}
+
+func (xxh64Hasher) Name() string   { return "xxh64" }
+func (xxh64Hasher) New() hash.Hash { return xxhash.New() }
+
+var availableHashers = map[string]Hasher{
+	"sha256": sha256Hasher{},
+	"blake3": blake3Hasher{},
+	"xxh64":  xxh64Hasher{},
+}
+
+// ParseHashers resolves a comma-separated --hash flag value, e.g.
+// "sha256,blake3", into the Hasher implementations Scan should run. An
+// empty spec defaults to sha256 alone.
+func ParseHashers(spec string) ([]Hasher, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = "sha256"
+	}
+
+	parts := strings.Split(spec, ",")
+	hashers := make([]Hasher, 0, len(parts))
+	for _, p := range parts {
+		name := strings.TrimSpace(p)
+		h, ok := availableHashers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown hash algorithm %q (available: sha256, blake3, xxh64)", name)
+		}
+		hashers = append(hashers, h)
+	}
+	return hashers, nil
+}
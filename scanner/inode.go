@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package scanner
+
+import "os"
+
+// inodeOf returns the inode number backing info, or 0 on platforms where
+// that concept doesn't map cleanly onto os.FileInfo (e.g. Windows).
+func inodeOf(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+// inodeOf returns the inode number backing info, read from the
+// underlying syscall.Stat_t that os.FileInfo.Sys() exposes on unix
+// platforms.
+func inodeOf(info os.FileInfo) uint64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return uint64(st.Ino)
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package scanner
+
+import "runtime"
+
+// defaultIOWorkers returns a sane default for --io-workers when the user
+// hasn't set one. On platforms where we can't cheaply tell spinning disks
+// from SSDs, fall back to a conservative fraction of NumCPU: high enough
+// to keep an SSD busy, low enough not to thrash a spinning disk with seeks.
+func defaultIOWorkers(root string) int {
+	n := runtime.NumCPU() / 2
+	if n < 2 {
+		n = 2
+	}
+	return n
+}
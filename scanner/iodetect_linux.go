@@ -0,0 +1,66 @@
+//go:build linux
+
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+// defaultIOWorkers returns a sane default for --io-workers when the user
+// hasn't set one. On Linux we can ask the kernel whether the block
+// device backing root is rotational (a spinning disk, where too much IO
+// concurrency just adds seek latency) or not (an SSD/NVMe, which wants
+// many outstanding reads). Any failure along the way falls back to the
+// generic NumCPU-based default rather than guessing wrong.
+func defaultIOWorkers(root string) int {
+	generic := func() int {
+		n := runtime.NumCPU() / 2
+		if n < 2 {
+			n = 2
+		}
+		return n
+	}()
+
+	rotational, err := isRotational(root)
+	if err != nil {
+		return generic
+	}
+	if rotational {
+		return 2
+	}
+	return runtime.NumCPU()
+}
+
+func isRotational(root string) (bool, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(root, &st); err != nil {
+		return false, err
+	}
+	major := uint64(st.Dev) >> 8 & 0xfff
+	minor := uint64(st.Dev) & 0xff
+
+	devDir := fmt.Sprintf("/sys/dev/block/%d:%d", major, minor)
+	target, err := os.Readlink(devDir)
+	if err != nil {
+		return false, err
+	}
+
+	// Partitions (e.g. sda1) link under the whole disk's directory
+	// (e.g. .../sda/sda1); the rotational flag lives one level up, on
+	// the whole-disk node.
+	candidate := filepath.Join("/sys/dev/block", target, "queue", "rotational")
+	if _, err := os.Stat(candidate); err != nil {
+		candidate = filepath.Join("/sys/dev/block", filepath.Dir(target), "queue", "rotational")
+	}
+
+	data, err := os.ReadFile(candidate)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(data)) == "1", nil
+}
@@ -0,0 +1,23 @@
+package scanner
+
+import (
+	"bytes"
+	"sync"
+)
+
+// bufferPool recycles the byte buffers IO workers read file contents
+// into before handing them off to a hash worker, so a tree with many
+// small files doesn't allocate and garbage-collect a fresh buffer per
+// file.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	buf.Reset()
+	bufferPool.Put(buf)
+}
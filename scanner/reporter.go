@@ -0,0 +1,40 @@
+package scanner
+
+import "time"
+
+// Stats summarizes a finished Scan, passed to Reporter.ScanFinished.
+type Stats struct {
+	FilesDiscovered int
+	FilesHashed     int
+	Errors          int
+	BytesHashed     int64
+	Duration        time.Duration
+}
+
+// Reporter observes scan progress. Scan calls its methods from whichever
+// goroutine reaches that point in the pipeline (the walk goroutine for
+// FileDiscovered, an IO worker for FileStarted, a hash worker for
+// FileHashed/FileError), so implementations must be safe for concurrent
+// use. A --resume/--db cache hit is reported as a zero-size
+// FileStarted immediately followed by FileHashed, both from the walk
+// goroutine, so counters that track in-flight work stay balanced even
+// though no IO/hash worker ever touches that file. This replaces a
+// hardcoded progressbar.Default call, which is unusable when the tool
+// is invoked from CI, cron, or another program.
+type Reporter interface {
+	FileDiscovered(path string)
+	FileStarted(path string, size int64)
+	FileHashed(path string, bytes int64, duration time.Duration)
+	FileError(path string, err error)
+	ScanFinished(stats Stats)
+}
+
+// NoopReporter discards every event. It backs --quiet, and is what Scan
+// falls back to when Options.Reporter is nil.
+type NoopReporter struct{}
+
+func (NoopReporter) FileDiscovered(path string)                                  {}
+func (NoopReporter) FileStarted(path string, size int64)                         {}
+func (NoopReporter) FileHashed(path string, bytes int64, duration time.Duration) {}
+func (NoopReporter) FileError(path string, err error)                            {}
+func (NoopReporter) ScanFinished(stats Stats)                                    {}
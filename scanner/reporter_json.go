@@ -0,0 +1,77 @@
+package scanner
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// jsonEvent is one line of --progress=json output.
+type jsonEvent struct {
+	Event       string  `json:"event"`
+	Path        string  `json:"path,omitempty"`
+	Size        int64   `json:"size,omitempty"`
+	BytesPerSec float64 `json:"bytes_per_sec,omitempty"`
+	WorkersBusy int32   `json:"workers_busy,omitempty"`
+	QueueDepth  int32   `json:"queue_depth,omitempty"`
+	Error       string  `json:"error,omitempty"`
+	Stats       *Stats  `json:"stats,omitempty"`
+}
+
+// JSONReporter emits one JSON object per line to an io.Writer (normally
+// stderr), so the tool can be plugged into log aggregators or render its
+// own progress without depending on a TTY.
+type JSONReporter struct {
+	mu          sync.Mutex
+	enc         *json.Encoder
+	workersBusy int32
+	queueDepth  int32
+	bytesTotal  int64
+	started     time.Time
+}
+
+// NewJSONReporter writes one JSON event per line to w as the scan
+// progresses.
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{enc: json.NewEncoder(w), started: time.Now()}
+}
+
+func (r *JSONReporter) emit(e jsonEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(e)
+}
+
+func (r *JSONReporter) FileDiscovered(path string) {
+	depth := atomic.AddInt32(&r.queueDepth, 1)
+	r.emit(jsonEvent{Event: "discovered", Path: path, QueueDepth: depth})
+}
+
+func (r *JSONReporter) FileStarted(path string, size int64) {
+	atomic.AddInt32(&r.queueDepth, -1)
+	busy := atomic.AddInt32(&r.workersBusy, 1)
+	r.emit(jsonEvent{Event: "started", Path: path, Size: size, WorkersBusy: busy})
+}
+
+func (r *JSONReporter) FileHashed(path string, bytes int64, duration time.Duration) {
+	busy := atomic.AddInt32(&r.workersBusy, -1)
+	total := atomic.AddInt64(&r.bytesTotal, bytes)
+
+	var throughput float64
+	if elapsed := time.Since(r.started).Seconds(); elapsed > 0 {
+		throughput = float64(total) / elapsed
+	}
+
+	r.emit(jsonEvent{Event: "hashed", Path: path, Size: bytes, BytesPerSec: throughput, WorkersBusy: busy})
+}
+
+func (r *JSONReporter) FileError(path string, err error) {
+	busy := atomic.AddInt32(&r.workersBusy, -1)
+	r.emit(jsonEvent{Event: "error", Path: path, WorkersBusy: busy, Error: err.Error()})
+}
+
+func (r *JSONReporter) ScanFinished(stats Stats) {
+	r.emit(jsonEvent{Event: "finished", Stats: &stats})
+}
@@ -0,0 +1,35 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// ProgressBarReporter renders a TTY progress bar: the tool's original
+// behavior, now expressed as one Reporter implementation among several.
+type ProgressBarReporter struct {
+	bar *progressbar.ProgressBar
+}
+
+// NewProgressBarReporter constructs a reporter backed by a progress bar
+// sized to totalFiles (pass 0 for an indeterminate spinner).
+func NewProgressBarReporter(totalFiles int) *ProgressBarReporter {
+	return &ProgressBarReporter{bar: progressbar.Default(int64(totalFiles))}
+}
+
+func (r *ProgressBarReporter) FileDiscovered(path string) {}
+
+func (r *ProgressBarReporter) FileStarted(path string, size int64) {}
+
+func (r *ProgressBarReporter) FileHashed(path string, bytes int64, duration time.Duration) {
+	_ = r.bar.Add(1)
+}
+
+func (r *ProgressBarReporter) FileError(path string, err error) {
+	_ = r.bar.Add(1)
+}
+
+func (r *ProgressBarReporter) ScanFinished(stats Stats) {
+	_ = r.bar.Finish()
+}
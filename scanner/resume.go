@@ -0,0 +1,31 @@
+package scanner
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// loadResumeState reads a previously saved inventory JSON file and indexes
+// it by resumeKey so the walker can skip re-hashing files that haven't
+// changed. A missing file is not an error: it just means this is a fresh
+// scan rather than a resumed one.
+func loadResumeState(path string) (map[string]FileInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]FileInfo{}, nil
+		}
+		return nil, err
+	}
+
+	var previous []FileInfo
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]FileInfo, len(previous))
+	for _, fi := range previous {
+		index[fi.resumeKey()] = fi
+	}
+	return index, nil
+}
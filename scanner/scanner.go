@@ -0,0 +1,482 @@
+// Package scanner implements the walk-and-hash pipeline behind
+// file-inventory: it walks a directory tree, hashes every regular file it
+// finds, and reports the results as a slice of FileInfo.
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// Format selects how Scan writes its results to Options.Output.
+type Format string
+
+const (
+	// FormatJSON writes a single JSON array once the scan finishes.
+	FormatJSON Format = "json"
+	// FormatNDJSON writes one JSON object per line as each result
+	// becomes available, so memory use stays O(WorkerCount) instead of
+	// O(files) and downstream tools can consume the inventory
+	// incrementally.
+	FormatNDJSON Format = "ndjson"
+)
+
+// DefaultMaxBytesInFlight bounds how many bytes of file content can be
+// read and waiting to be hashed at once, so a handful of huge files
+// can't balloon memory the way unbounded goroutine-per-file concurrency
+// would.
+const DefaultMaxBytesInFlight = 256 << 20 // 256 MiB
+
+// Options configures a Scan.
+type Options struct {
+	// RootDir is the directory to walk.
+	RootDir string
+	// IOWorkers bounds how many files are being read from disk at once.
+	// Defaults to a value chosen from the root device's rotational
+	// flag: a small number on spinning disks, NumCPU on SSD/NVMe.
+	IOWorkers int
+	// HashWorkers bounds how many files are being hashed (a CPU-bound
+	// step) at once. Defaults to runtime.NumCPU().
+	HashWorkers int
+	// MaxBytesInFlight bounds the total size of file content that has
+	// been read but not yet hashed. Defaults to DefaultMaxBytesInFlight.
+	MaxBytesInFlight int64
+	// ResumeFrom, if non-empty, is the path to a previous inventory JSON
+	// file. Files whose path, size and modification time match an entry
+	// in it are not re-hashed; the cached hash is reused instead.
+	ResumeFrom string
+	// Hash is a comma-separated list of algorithms to compute for each
+	// file, e.g. "sha256,blake3". Defaults to "sha256".
+	Hash string
+	// ChunkHashes, if true, additionally splits each file into
+	// content-defined chunks and records a SHA256 per chunk, so two
+	// FileInfo entries can be diffed for shared chunks.
+	ChunkHashes bool
+	// DBPath, if non-empty, is the path to a bbolt database that caches
+	// {size, mtime, inode, hash} per absolute path across runs. Like
+	// ResumeFrom this lets unchanged files skip re-hashing, but it
+	// persists across invocations on its own rather than needing the
+	// previous JSON output as input, and reports files that disappeared
+	// from the tree since the last scan. Takes precedence over
+	// ResumeFrom if both are set.
+	DBPath string
+	// Format selects the output encoding. Defaults to FormatJSON.
+	Format Format
+	// Output receives the encoded results. Required.
+	Output io.Writer
+	// Reporter observes scan progress. Defaults to NoopReporter{}.
+	Reporter Reporter
+}
+
+// Result is the outcome of a Scan: how many files were inventoried, how
+// many errors were encountered along the way, and whether the walk was
+// cancelled before it finished (in which case the output written to
+// Options.Output is a partial inventory rather than the full tree).
+type Result struct {
+	FileCount  int
+	ErrorCount int
+	Cancelled  bool
+	// Deleted lists the absolute paths of files recorded in the --db
+	// store on a previous scan that weren't seen on this one. Only
+	// populated when Options.DBPath is set, and left empty on a
+	// cancelled scan since an unvisited path there just means the walk
+	// didn't reach it yet, not that it's gone.
+	Deleted []string
+}
+
+// pathTask is a file discovered by the walker, queued up for an IO
+// worker to read and a hash worker to hash, in walk order.
+type pathTask struct {
+	path    string
+	absPath string
+	entry   FileInfo
+	inode   uint64
+	seq     int
+}
+
+// readTask is a pathTask whose content an IO worker has already read
+// into a pooled buffer, ready for a hash worker to consume.
+type readTask struct {
+	path    string
+	absPath string
+	entry   FileInfo
+	inode   uint64
+	seq     int
+	buf     *bytes.Buffer
+	bytes   int64
+	// started marks when the IO worker began reading, so the hash worker
+	// can report FileHashed's duration end to end rather than just the
+	// hashing step.
+	started time.Time
+}
+
+// Scan walks fs starting at opts.RootDir, hashing every regular file and
+// writing each FileInfo to opts.Output in the format selected by
+// opts.Format, in the order the walk discovered them regardless of which
+// worker finishes first. It stops early and returns a partial Result if
+// ctx is cancelled: in-flight workers are allowed to finish (so no
+// half-written hash ever reaches the output), but no new file is started
+// once cancellation is observed.
+//
+// Reading and hashing run as two separate worker pools connected by a
+// channel, so IOWorkers (bounded by disk parallelism) and HashWorkers
+// (bounded by CPU count) can be tuned independently, and a
+// semaphore.Weighted sized in bytes rather than goroutines keeps a
+// handful of huge files from starving a flood of small ones.
+func Scan(ctx context.Context, fs Filesystem, opts Options) (Result, error) {
+	resumeIndex := map[string]FileInfo{}
+	if opts.ResumeFrom != "" {
+		idx, err := loadResumeState(opts.ResumeFrom)
+		if err != nil {
+			return Result{}, fmt.Errorf("loading resume state: %w", err)
+		}
+		resumeIndex = idx
+	}
+
+	hashers, err := ParseHashers(opts.Hash)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var store *Store
+	var scanID int64
+	if opts.DBPath != "" {
+		store, err = OpenStore(opts.DBPath)
+		if err != nil {
+			return Result{}, fmt.Errorf("opening db: %w", err)
+		}
+		defer store.Close()
+		scanID, err = store.NextScanID()
+		if err != nil {
+			return Result{}, fmt.Errorf("allocating scan id: %w", err)
+		}
+	}
+
+	format := opts.Format
+	if format == "" {
+		format = FormatJSON
+	}
+
+	ioWorkers := opts.IOWorkers
+	if ioWorkers <= 0 {
+		ioWorkers = defaultIOWorkers(opts.RootDir)
+	}
+	hashWorkers := opts.HashWorkers
+	if hashWorkers <= 0 {
+		hashWorkers = defaultHashWorkers()
+	}
+	maxBytesInFlight := opts.MaxBytesInFlight
+	if maxBytesInFlight <= 0 {
+		maxBytesInFlight = DefaultMaxBytesInFlight
+	}
+
+	reporter := opts.Reporter
+	if reporter == nil {
+		reporter = NoopReporter{}
+	}
+	scanStart := time.Now()
+	var bytesHashed int64
+	var bytesHashedMu sync.Mutex
+
+	var jsonArray []FileInfo
+	var writeMu sync.Mutex
+	var writeErr error
+	enc := json.NewEncoder(opts.Output)
+
+	emit := func(fi FileInfo) error {
+		if format == FormatNDJSON {
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			return enc.Encode(fi)
+		}
+		jsonArray = append(jsonArray, fi)
+		return nil
+	}
+	seq := newSequencer(emit)
+
+	byteSem := semaphore.NewWeighted(maxBytesInFlight)
+
+	pathChan := make(chan pathTask, ioWorkers*2)
+	readChan := make(chan readTask, hashWorkers*2)
+
+	var fileCount int
+	var fileCountMu sync.Mutex
+	var errorCount int
+	var errorMu sync.Mutex
+	recordError := func(path string, err error) {
+		fmt.Fprintf(os.Stderr, "Processing error: %v\n", err)
+		errorMu.Lock()
+		errorCount++
+		errorMu.Unlock()
+		reporter.FileError(path, err)
+	}
+	recordSuccess := func() {
+		fileCountMu.Lock()
+		fileCount++
+		fileCountMu.Unlock()
+	}
+
+	var ioWg, hashWg sync.WaitGroup
+
+	// IO workers: read each file's content into a pooled buffer, gated
+	// by how many bytes are already in flight.
+	ioWg.Add(ioWorkers)
+	for i := 0; i < ioWorkers; i++ {
+		go func() {
+			defer ioWg.Done()
+			for task := range pathChan {
+				weight := task.entry.Size
+				if weight > maxBytesInFlight {
+					weight = maxBytesInFlight
+				}
+				if weight < 1 {
+					weight = 1
+				}
+				if err := byteSem.Acquire(ctx, weight); err != nil {
+					// Context cancelled while waiting for room: drop
+					// this file rather than block the pipeline.
+					_ = seq.skip(task.seq)
+					continue
+				}
+
+				reporter.FileStarted(task.path, task.entry.Size)
+				started := time.Now()
+				buf, n, err := readFile(fs, task.path)
+				if err != nil {
+					byteSem.Release(weight)
+					recordError(task.path, fmt.Errorf("error reading %s: %w", task.path, err))
+					_ = seq.skip(task.seq)
+					continue
+				}
+
+				// weight is released by the hash worker once hashing
+				// has consumed the buffer.
+				readChan <- readTask{path: task.path, absPath: task.absPath, entry: task.entry, inode: task.inode, seq: task.seq, buf: buf, bytes: n, started: started}
+			}
+		}()
+	}
+
+	// Hash workers: hash the buffered content, release it back to the
+	// pool, and release the bytes-in-flight semaphore.
+	hashWg.Add(hashWorkers)
+	for i := 0; i < hashWorkers; i++ {
+		go func() {
+			defer hashWg.Done()
+			for task := range readChan {
+				weight := task.entry.Size
+				if weight > maxBytesInFlight {
+					weight = maxBytesInFlight
+				}
+				if weight < 1 {
+					weight = 1
+				}
+
+				task.entry.Hashes = hashBuffer(task.buf, hashers)
+				if opts.ChunkHashes {
+					task.entry.Chunks = chunkData(task.buf.Bytes())
+				}
+				putBuffer(task.buf)
+				byteSem.Release(weight)
+
+				if store != nil {
+					dbErr := store.put(task.absPath, dbEntry{
+						Size:           task.entry.Size,
+						ModTime:        task.entry.ModifiedDate,
+						Inode:          task.inode,
+						Hashes:         task.entry.Hashes,
+						Chunks:         task.entry.Chunks,
+						LastSeenScanID: scanID,
+					})
+					if dbErr != nil {
+						recordError(task.path, fmt.Errorf("error updating db for %s: %w", task.path, dbErr))
+					}
+				}
+
+				bytesHashedMu.Lock()
+				bytesHashed += task.bytes
+				bytesHashedMu.Unlock()
+				reporter.FileHashed(task.path, task.bytes, time.Since(task.started))
+
+				recordSuccess()
+				if err := seq.submit(task.seq, task.entry); err != nil {
+					writeMu.Lock()
+					if writeErr == nil {
+						writeErr = err
+					}
+					writeMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	nextSeq := 0
+	cancelled := false
+	walkErr := fs.Walk(opts.RootDir, func(path string, info os.FileInfo, err error) error {
+		select {
+		case <-ctx.Done():
+			cancelled = true
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return ctx.Err()
+		default:
+		}
+
+		if err != nil {
+			if os.IsPermission(err) {
+				fmt.Fprintf(os.Stderr, "Permission error accessing: %s (skipping)\n", path)
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Error accessing %s: %v (skipping)\n", path, err)
+			errorMu.Lock()
+			errorCount++
+			errorMu.Unlock()
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		fileMode := info.Mode()
+		if fileMode&os.ModeNamedPipe != 0 ||
+			fileMode&os.ModeSocket != 0 ||
+			fileMode&os.ModeDevice != 0 ||
+			fileMode&os.ModeCharDevice != 0 ||
+			fileMode&os.ModeSymlink != 0 {
+			fmt.Fprintf(os.Stderr, "Skipping special file or symlink: %s\n", path)
+			return nil
+		}
+
+		dir, fileName := filepath.Split(path)
+		entry := FileInfo{
+			Name:         fileName,
+			Path:         dir,
+			Size:         info.Size(),
+			ModifiedDate: info.ModTime().UTC().Format("2006-01-02T15:04:05.999999999Z"),
+		}
+
+		mySeq := nextSeq
+		nextSeq++
+		reporter.FileDiscovered(path)
+
+		if store != nil {
+			absPath, absErr := filepath.Abs(path)
+			if absErr != nil {
+				absPath = path
+			}
+			inode := inodeOf(info)
+
+			if cached, ok, lookupErr := store.lookup(absPath); lookupErr == nil && ok &&
+				cached.unchanged(entry.Size, entry.ModifiedDate) && cached.satisfies(hashers, opts.ChunkHashes) {
+				entry.Hashes = cached.Hashes
+				entry.Chunks = cached.Chunks
+				if touchErr := store.touch(absPath, cached, scanID); touchErr != nil {
+					recordError(path, fmt.Errorf("error updating db for %s: %w", path, touchErr))
+				}
+				recordSuccess()
+				reporter.FileStarted(path, 0)
+				reporter.FileHashed(path, 0, 0)
+				if err := seq.submit(mySeq, entry); err != nil {
+					writeMu.Lock()
+					if writeErr == nil {
+						writeErr = err
+					}
+					writeMu.Unlock()
+				}
+				return nil
+			}
+
+			pathChan <- pathTask{path: path, absPath: absPath, entry: entry, inode: inode, seq: mySeq}
+			return nil
+		}
+
+		if cached, ok := resumeIndex[entry.resumeKey()]; ok && cached.satisfies(hashers, opts.ChunkHashes) {
+			recordSuccess()
+			reporter.FileStarted(path, 0)
+			reporter.FileHashed(path, 0, 0)
+			if err := seq.submit(mySeq, cached); err != nil {
+				writeMu.Lock()
+				if writeErr == nil {
+					writeErr = err
+				}
+				writeMu.Unlock()
+			}
+			return nil
+		}
+
+		pathChan <- pathTask{path: path, entry: entry, seq: mySeq}
+		return nil
+	})
+
+	close(pathChan)
+	ioWg.Wait()
+	close(readChan)
+	hashWg.Wait()
+
+	// Skip the sweep on a cancelled scan: every path the walk hadn't
+	// reached yet would otherwise look "not seen this scan" and get
+	// reported (and purged from the store) as deleted, even though it's
+	// still on disk and simply wasn't visited this run.
+	var deleted []string
+	if store != nil && !cancelled {
+		deleted, err = store.sweep(scanID)
+		if err != nil {
+			return Result{}, fmt.Errorf("sweeping db: %w", err)
+		}
+	}
+
+	if format == FormatJSON {
+		if data, err := json.Marshal(jsonArray); err != nil {
+			if writeErr == nil {
+				writeErr = err
+			}
+		} else if _, err := opts.Output.Write(data); err != nil {
+			if writeErr == nil {
+				writeErr = err
+			}
+		}
+	}
+
+	result := Result{FileCount: fileCount, ErrorCount: errorCount, Cancelled: cancelled, Deleted: deleted}
+
+	reporter.ScanFinished(Stats{
+		FilesDiscovered: nextSeq,
+		FilesHashed:     fileCount,
+		Errors:          errorCount,
+		BytesHashed:     bytesHashed,
+		Duration:        time.Since(scanStart),
+	})
+
+	if walkErr != nil && walkErr != ctx.Err() {
+		return result, fmt.Errorf("critical error during directory walk: %w", walkErr)
+	}
+	if writeErr != nil {
+		return result, fmt.Errorf("writing results: %w", writeErr)
+	}
+
+	return result, nil
+}
+
+// SaveToJSON writes files as a JSON array to outputPath. It's a small
+// convenience for callers (and the resume loader) that already have a
+// full slice in memory, as opposed to Scan's streaming output path.
+func SaveToJSON(files []FileInfo, outputPath string) error {
+	jsonData, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, jsonData, 0644)
+}
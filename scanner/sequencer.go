@@ -0,0 +1,64 @@
+package scanner
+
+import "sync"
+
+// sequencer reorders results that arrive out of order (hashing workers
+// finish in whatever order their I/O completes) back into the order the
+// walker discovered them in, so streaming output is deterministic and
+// independent of scheduling. The approach mirrors the sequencer used by
+// gofmt's concurrent formatter: callers submit (seq, value) pairs as they
+// become available, and emit is called for each value in strictly
+// increasing seq order as soon as the run becomes contiguous.
+type sequencer struct {
+	mu      sync.Mutex
+	next    int
+	pending map[int]*FileInfo
+	emit    func(FileInfo) error
+}
+
+func newSequencer(emit func(FileInfo) error) *sequencer {
+	return &sequencer{
+		pending: make(map[int]*FileInfo),
+		emit:    emit,
+	}
+}
+
+// submit records the result for seq and emits every contiguous run of
+// results starting at the next expected sequence number. It returns the
+// first error returned by emit, if any; submission continues regardless
+// so the sequencer never deadlocks waiting for a seq number that will
+// never arrive.
+func (s *sequencer) submit(seq int, fi FileInfo) error {
+	return s.resolve(seq, &fi)
+}
+
+// skip marks seq as resolved without emitting anything for it, e.g. when
+// hashing that file failed. It still advances the contiguous run so
+// later seq numbers aren't stuck waiting on one that will never emit.
+func (s *sequencer) skip(seq int) error {
+	return s.resolve(seq, nil)
+}
+
+func (s *sequencer) resolve(seq int, fi *FileInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending[seq] = fi
+
+	var firstErr error
+	for {
+		next, ok := s.pending[s.next]
+		if !ok {
+			break
+		}
+		delete(s.pending, s.next)
+		s.next++
+		if next == nil {
+			continue
+		}
+		if err := s.emit(*next); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSequencerOrdersOutOfOrderSubmits(t *testing.T) {
+	var got []FileInfo
+	seq := newSequencer(func(fi FileInfo) error {
+		got = append(got, fi)
+		return nil
+	})
+
+	if err := seq.submit(2, FileInfo{Name: "c"}); err != nil {
+		t.Fatalf("submit(2): %v", err)
+	}
+	if err := seq.submit(0, FileInfo{Name: "a"}); err != nil {
+		t.Fatalf("submit(0): %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "a" {
+		t.Fatalf("expected only seq 0 to have emitted so far, got %v", got)
+	}
+	if err := seq.submit(1, FileInfo{Name: "b"}); err != nil {
+		t.Fatalf("submit(1): %v", err)
+	}
+
+	want := []FileInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSequencerSkipAdvancesWithoutEmitting(t *testing.T) {
+	var got []FileInfo
+	seq := newSequencer(func(fi FileInfo) error {
+		got = append(got, fi)
+		return nil
+	})
+
+	if err := seq.submit(0, FileInfo{Name: "a"}); err != nil {
+		t.Fatalf("submit(0): %v", err)
+	}
+	if err := seq.skip(1); err != nil {
+		t.Fatalf("skip(1): %v", err)
+	}
+	if err := seq.submit(2, FileInfo{Name: "c"}); err != nil {
+		t.Fatalf("submit(2): %v", err)
+	}
+
+	want := []FileInfo{{Name: "a"}, {Name: "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSequencerReturnsFirstEmitError(t *testing.T) {
+	errA := errors.New("emit a failed")
+	seq := newSequencer(func(fi FileInfo) error {
+		if fi.Name == "a" {
+			return errA
+		}
+		return nil
+	})
+
+	if err := seq.submit(1, FileInfo{Name: "b"}); err != nil {
+		t.Fatalf("submit(1): %v", err)
+	}
+	if err := seq.submit(0, FileInfo{Name: "a"}); !errors.Is(err, errA) {
+		t.Fatalf("submit(0) error = %v, want %v", err, errA)
+	}
+}